@@ -0,0 +1,30 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analyzer exposes tryhard's analysis as an *analysis.Analyzer, so
+// it can be plugged into multichecker, golangci-lint, and gopls.
+package analyzer
+
+import (
+	"github.com/griesemer/tryhard"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports statements that are candidates for the `try` builtin.
+var Analyzer = &analysis.Analyzer{
+	Name: "tryhard",
+	Doc:  "report statements that are candidates for the `try` builtin",
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	rep := tryhard.Analyze(pass.Fset, pass.Files, tryhard.Options{})
+	for _, f := range rep.Findings {
+		if f.Kind != tryhard.TryCand {
+			continue
+		}
+		pass.Reportf(f.Pos, "candidate for try: %s", tryhard.KindDesc[f.Kind])
+	}
+	return nil, nil
+}
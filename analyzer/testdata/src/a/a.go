@@ -0,0 +1,12 @@
+package a
+
+func g() (int, error) { return 0, nil }
+
+func f() error {
+	v, err := g()
+	if err != nil { // want "candidate for try: try candidates"
+		return err
+	}
+	_ = v
+	return nil
+}
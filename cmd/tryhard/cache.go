@@ -0,0 +1,195 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/griesemer/tryhard"
+)
+
+// cacheKey identifies a cached *tryhard.Report for src. It is derived from
+// the file content and from -err, since -err changes which IfErr statements
+// match and thus changes the findings; it does not depend on -r or -defer,
+// which only control whether (and how) matches get rewritten, not which
+// statements are found.
+func cacheKey(src []byte) string {
+	h := sha256.New()
+	h.Write(src)
+	h.Write([]byte{0})
+	h.Write([]byte(*varname))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheDir returns the directory tryhard caches findings in, creating it if
+// necessary.
+func cacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "tryhard")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cacheEntry is the on-disk (JSON) representation of a cached *tryhard.Report
+// for a single file. Findings are stored by line and column rather than by
+// token.Pos, since a token.Pos is only meaningful relative to the token.FileSet
+// that produced it, which does not survive across runs.
+type cacheEntry struct {
+	Counts   map[string]int `json:"counts"`
+	Findings []cacheFinding `json:"findings"`
+}
+
+type cacheFinding struct {
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Kind     string `json:"kind"`
+	FuncName string `json:"funcName,omitempty"`
+	ErrName  string `json:"errName,omitempty"`
+}
+
+// loadCache looks up key in the on-disk cache and, on a hit, reconstructs a
+// *tryhard.Report for filename whose Finding.Pos values resolve correctly
+// through the package-global fset, as if filename had just been parsed.
+func loadCache(key, filename string, src []byte) (*tryhard.Report, bool) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+
+	tf := fset.AddFile(filename, -1, len(src))
+	tf.SetLinesForContent(src)
+
+	rep := new(tryhard.Report)
+	for name, n := range entry.Counts {
+		if k, ok := kindByName(name); ok {
+			rep.Counts[k] += n
+		}
+	}
+	for _, cf := range entry.Findings {
+		k, ok := kindByName(cf.Kind)
+		if !ok {
+			continue
+		}
+		pos := tf.LineStart(cf.Line) + token.Pos(cf.Col-1)
+		rep.Findings = append(rep.Findings, tryhard.Finding{
+			Kind:     k,
+			Pos:      pos,
+			FuncName: cf.FuncName,
+			ErrName:  cf.ErrName,
+		})
+	}
+	touchCache(filepath.Join(dir, key+".json"))
+	return rep, true
+}
+
+// storeCache writes rep's findings for the file they came from under key,
+// then runs a size-based eviction pass.
+func storeCache(key string, rep *tryhard.Report) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+
+	entry := cacheEntry{Counts: make(map[string]int, len(rep.Counts))}
+	for k, n := range rep.Counts {
+		if n != 0 {
+			entry.Counts[tryhard.KindName[k]] = n
+		}
+	}
+	for _, find := range rep.Findings {
+		p := fset.Position(find.Pos)
+		entry.Findings = append(entry.Findings, cacheFinding{
+			Line:     p.Line,
+			Col:      p.Column,
+			Kind:     tryhard.KindName[find.Kind],
+			FuncName: find.FuncName,
+			ErrName:  find.ErrName,
+		})
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	ioutil.WriteFile(filepath.Join(dir, key+".json"), data, 0644)
+
+	evictCache(dir)
+}
+
+// touchCache bumps path's modification time so the LRU eviction pass in
+// evictCache treats it as recently used.
+func touchCache(path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// evictCache removes the least-recently-used entries from dir until its
+// total size is at or below *cacheMaxMB.
+func evictCache(dir string) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	maxBytes := *cacheMaxMB * 1024 * 1024
+	var total int64
+	for _, fi := range entries {
+		total += fi.Size()
+	}
+	for _, fi := range entries {
+		if total <= maxBytes {
+			break
+		}
+		if os.Remove(filepath.Join(dir, fi.Name())) == nil {
+			total -= fi.Size()
+		}
+	}
+}
+
+// cleanCache removes the entire on-disk findings cache.
+func cleanCache() error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("cleaning cache: %v", err)
+	}
+	return nil
+}
+
+func kindByName(name string) (tryhard.Kind, bool) {
+	for k, n := range tryhard.KindName {
+		if n == name {
+			return tryhard.Kind(k), true
+		}
+	}
+	return 0, false
+}
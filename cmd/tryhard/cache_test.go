@@ -0,0 +1,105 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/griesemer/tryhard"
+)
+
+func TestCacheKey(t *testing.T) {
+	before := *varname
+	defer func() { *varname = before }()
+
+	src := []byte("package p\n")
+	*varname = ""
+	k1 := cacheKey(src)
+	if k2 := cacheKey(src); k1 != k2 {
+		t.Errorf("cacheKey is not deterministic: %q != %q", k1, k2)
+	}
+
+	*varname = "e"
+	if k3 := cacheKey(src); k3 == k1 {
+		t.Errorf("cacheKey(%q) with -err=%q collided with -err=%q", src, "e", "")
+	}
+}
+
+func TestStoreAndLoadCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	src := []byte("package p\n\nfunc f() error {\n\terr := g()\n\tif err != nil {\n\t\treturn err\n\t}\n\treturn nil\n}\n")
+	filename := "f.go"
+	key := cacheKey(src)
+
+	want := new(tryhard.Report)
+	want.Counts[tryhard.Func] = 1
+	want.Counts[tryhard.TryCand] = 1
+	pos := fset.AddFile(filename, -1, len(src))
+	pos.SetLinesForContent(src)
+	want.Findings = append(want.Findings, tryhard.Finding{
+		Kind:     tryhard.TryCand,
+		Pos:      pos.LineStart(4),
+		FuncName: "f",
+		ErrName:  "err",
+	})
+
+	if _, ok := loadCache(key, filename, src); ok {
+		t.Fatalf("loadCache found an entry before storeCache was called")
+	}
+
+	storeCache(key, want)
+
+	got, ok := loadCache(key, filename, src)
+	if !ok {
+		t.Fatalf("loadCache did not find the entry just stored")
+	}
+	if got.Counts != want.Counts {
+		t.Errorf("Counts = %v, want %v", got.Counts, want.Counts)
+	}
+	if len(got.Findings) != len(want.Findings) {
+		t.Fatalf("len(Findings) = %d, want %d", len(got.Findings), len(want.Findings))
+	}
+	gf, wf := got.Findings[0], want.Findings[0]
+	if gf.Kind != wf.Kind || gf.FuncName != wf.FuncName || gf.ErrName != wf.ErrName {
+		t.Errorf("Findings[0] = %+v, want %+v", gf, wf)
+	}
+	if fset.Position(gf.Pos) != fset.Position(wf.Pos) {
+		t.Errorf("Findings[0].Pos resolves to %v, want %v", fset.Position(gf.Pos), fset.Position(wf.Pos))
+	}
+}
+
+func TestEvictCacheRemovesLeastRecentlyUsed(t *testing.T) {
+	beforeMax := *cacheMaxMB
+	defer func() { *cacheMaxMB = beforeMax }()
+
+	dir := t.TempDir()
+	storeKeyedEntry := func(key string) string {
+		path := filepath.Join(dir, key+".json")
+		if err := os.WriteFile(path, make([]byte, 1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	old := storeKeyedEntry("old")
+	storeKeyedEntry("new")
+	// Back-date "old" so it is evicted ahead of "new" regardless of
+	// filesystem mtime resolution.
+	epoch := time.Unix(0, 0)
+	if err := os.Chtimes(old, epoch, epoch); err != nil {
+		t.Fatal(err)
+	}
+
+	*cacheMaxMB = 0 // force eviction down to (almost) nothing
+	evictCache(dir)
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("evictCache did not remove the least-recently-used entry")
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+
+	"github.com/griesemer/tryhard"
+)
+
+// sortFindings sorts rep.Findings by file name and line, so that -l,
+// -format=json, and -format=sarif output is deterministic regardless of the
+// order in which concurrent workers finished scanning files.
+func sortFindings(rep *tryhard.Report) {
+	sort.Slice(rep.Findings, func(i, j int) bool {
+		return posLess(rep.Findings[i].Pos, rep.Findings[j].Pos)
+	})
+}
+
+func posLess(a, b token.Pos) bool {
+	pa := fset.Position(a)
+	pb := fset.Position(b)
+	if pa.Filename != pb.Filename {
+		return pa.Filename < pb.Filename
+	}
+	return pa.Line < pb.Line
+}
+
+func reportCounts(rep *tryhard.Report) {
+	fmt.Println("--- stats ---")
+	reportCount(rep, tryhard.Func, tryhard.Func)
+	reportCount(rep, tryhard.FuncError, tryhard.Func)
+	reportCount(rep, tryhard.Stmt, tryhard.Stmt)
+	reportCount(rep, tryhard.If, tryhard.Stmt)
+	reportCount(rep, tryhard.IfErr, tryhard.If)
+	reportCount(rep, tryhard.TryCand, tryhard.IfErr)
+	reportCount(rep, tryhard.DeferWrapCand, tryhard.IfErr)
+	reportCount(rep, tryhard.NonErrName, tryhard.IfErr)
+
+	help := ""
+	if !*list {
+		help = " (-l flag lists file positions)"
+	}
+	fmt.Printf("--- non-try candidates%s ---\n", help)
+	reportCount(rep, tryhard.ReturnExpr, tryhard.IfErr)
+	reportCount(rep, tryhard.SingleStmt, tryhard.IfErr)
+	reportCount(rep, tryhard.ComplexBlock, tryhard.IfErr)
+	reportCount(rep, tryhard.HasElse, tryhard.IfErr)
+}
+
+func reportCount(rep *tryhard.Report, k, ofk tryhard.Kind) {
+	x := rep.Counts[k]
+	total := rep.Counts[ofk]
+	// don't crash if total == 0
+	p := 100.0 // percentage
+	if total != 0 {
+		p = float64(x) * 100 / float64(total)
+	}
+	fmt.Printf("% 7d (%5.1f%% of % 7d) %s\n", x, p, total, tryhard.KindDesc[k])
+}
+
+func reportPositions(rep *tryhard.Report) {
+	for k := tryhard.Kind(0); k < tryhard.NumKinds; k++ {
+		var list []tryhard.Finding
+		for _, find := range rep.Findings {
+			if find.Kind == k {
+				list = append(list, find)
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+		fmt.Printf("--- %s ---\n", tryhard.KindDesc[k])
+		for i, find := range list {
+			p := fset.Position(find.Pos)
+			fmt.Printf("% 7d  %s:%d\n", i+1, p.Filename, p.Line)
+		}
+		fmt.Println()
+	}
+}
+
+// jsonFinding is one entry of the "findings" array produced by -format=json.
+type jsonFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Kind     string `json:"kind"`
+	KindDesc string `json:"kindDesc"`
+	FuncName string `json:"funcName,omitempty"`
+	ErrName  string `json:"errName,omitempty"`
+}
+
+// jsonReport is the top-level object produced by -format=json.
+type jsonReport struct {
+	Findings []jsonFinding  `json:"findings"`
+	Summary  map[string]int `json:"summary"`
+}
+
+// reportJSON prints rep's findings and summary counts as a single JSON
+// object, for editors, CI dashboards, and other tools that consume
+// structured tryhard output.
+func reportJSON(rep *tryhard.Report) {
+	out := jsonReport{
+		Findings: make([]jsonFinding, len(rep.Findings)),
+		Summary:  make(map[string]int, len(tryhard.KindName)),
+	}
+	for i, find := range rep.Findings {
+		p := fset.Position(find.Pos)
+		out.Findings[i] = jsonFinding{
+			File:     p.Filename,
+			Line:     p.Line,
+			Col:      p.Column,
+			Kind:     tryhard.KindName[find.Kind],
+			KindDesc: tryhard.KindDesc[find.Kind],
+			FuncName: find.FuncName,
+			ErrName:  find.ErrName,
+		}
+	}
+	for k, n := range rep.Counts {
+		out.Summary[tryhard.KindName[k]] = n
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		report(err)
+	}
+}
+
+// sarifVersion is the SARIF schema version produced by reportSARIF.
+const sarifVersion = "2.1.0"
+
+const sarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string    `json:"id"`
+	ShortDescription sarifText `json:"shortDescription"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// reportSARIF prints rep's findings as a SARIF 2.1.0 log, with one rule per
+// non-zero Kind, so tryhard results can be uploaded to GitHub code scanning
+// or consumed by golangci-lint's SARIF reporter.
+func reportSARIF(rep *tryhard.Report) {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "tryhard",
+				InformationURI: "https://github.com/griesemer/tryhard",
+			},
+		},
+	}
+	for k, n := range rep.Counts {
+		if n == 0 {
+			continue
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               tryhard.KindName[k],
+			ShortDescription: sarifText{tryhard.KindDesc[k]},
+		})
+	}
+	for _, find := range rep.Findings {
+		p := fset.Position(find.Pos)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  tryhard.KindName[find.Kind],
+			Level:   "note",
+			Message: sarifText{tryhard.KindDesc[find.Kind]},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: p.Filename},
+					Region:           sarifRegion{StartLine: p.Line, StartColumn: p.Column},
+				},
+			}},
+		})
+	}
+
+	log := sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(log); err != nil {
+		report(err)
+	}
+}
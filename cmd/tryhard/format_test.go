@@ -0,0 +1,185 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/griesemer/tryhard"
+)
+
+const formatTestSrc = `package p
+
+func f() error {
+	err := g()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func g() error { return nil }
+`
+
+// formatTestReport parses formatTestSrc into the package-global fset and
+// returns its sorted *tryhard.Report, the way main does before calling
+// reportJSON/reportSARIF.
+func formatTestReport(t *testing.T) *tryhard.Report {
+	t.Helper()
+	file, err := parser.ParseFile(fset, "golden.go", formatTestSrc, 0)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	rep := tryhard.Analyze(fset, []*ast.File{file}, tryhard.Options{})
+	sortFindings(rep)
+	return rep
+}
+
+// captureStdout runs f with os.Stdout redirected to a pipe and returns
+// everything f wrote to it.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	f()
+
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+const wantJSON = `{
+  "findings": [
+    {
+      "file": "golden.go",
+      "line": 5,
+      "col": 2,
+      "kind": "TryCand",
+      "kindDesc": "try candidates",
+      "funcName": "f",
+      "errName": "err"
+    }
+  ],
+  "summary": {
+    "ComplexBlock": 0,
+    "DeferWrapCand": 0,
+    "Func": 2,
+    "FuncError": 2,
+    "HasElse": 0,
+    "If": 1,
+    "IfErr": 1,
+    "NonErrName": 0,
+    "ReturnExpr": 0,
+    "SingleStmt": 0,
+    "Stmt": 5,
+    "TryCand": 1
+  }
+}
+`
+
+func TestReportJSON(t *testing.T) {
+	rep := formatTestReport(t)
+	got := captureStdout(t, func() { reportJSON(rep) })
+	if got != wantJSON {
+		t.Errorf("reportJSON output:\n%s\nwant:\n%s", got, wantJSON)
+	}
+}
+
+const wantSARIF = `{
+  "$schema": "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "tryhard",
+          "informationUri": "https://github.com/griesemer/tryhard",
+          "rules": [
+            {
+              "id": "Func",
+              "shortDescription": {
+                "text": "functions (function literals are ignored)"
+              }
+            },
+            {
+              "id": "FuncError",
+              "shortDescription": {
+                "text": "functions returning an error"
+              }
+            },
+            {
+              "id": "Stmt",
+              "shortDescription": {
+                "text": "statements in functions returning an error"
+              }
+            },
+            {
+              "id": "If",
+              "shortDescription": {
+                "text": "if statements"
+              }
+            },
+            {
+              "id": "IfErr",
+              "shortDescription": {
+                "text": "if <err> != nil statements"
+              }
+            },
+            {
+              "id": "TryCand",
+              "shortDescription": {
+                "text": "try candidates"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "TryCand",
+          "level": "note",
+          "message": {
+            "text": "try candidates"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "golden.go"
+                },
+                "region": {
+                  "startLine": 5,
+                  "startColumn": 2
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+func TestReportSARIF(t *testing.T) {
+	rep := formatTestReport(t)
+	got := captureStdout(t, func() { reportSARIF(rep) })
+	if got != wantSARIF {
+		t.Errorf("reportSARIF output:\n%s\nwant:\n%s", got, wantSARIF)
+	}
+}
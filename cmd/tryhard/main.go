@@ -0,0 +1,321 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// `tryhard` is a simple tool to list and rewrite `try` candidate statements.
+// See README.md for details. It is a thin CLI wrapper around the tryhard
+// library package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/griesemer/tryhard"
+)
+
+// maxOpenFiles bounds the number of files concurrently open for
+// reading, independent of the number of worker goroutines, so that
+// scanning a large tree doesn't exhaust file descriptors.
+const maxOpenFiles = 20
+
+var (
+	// main operation modes
+	list    = flag.Bool("l", false, "list positions of potential `try` candidate statements")
+	rewrite = flag.Bool("r", false, "rewrite potential `try` candidate statements to use `try`")
+
+	// customization
+	varname      = flag.String("err", "", `name of error variable; using "" permits any name`)
+	filter       = flag.String("ignore", "vendor", "ignore files with paths matching this (regexp) pattern")
+	deferRewrite = flag.Bool("defer", false, "also rewrite wrap-and-return patterns (DeferWrapCand) using a deferred error decorator; implies -r and renames the error result")
+	outFormat    = flag.String("format", "text", "report format: text, json, or sarif")
+
+	// caching
+	cacheMode  = flag.String("cache", "off", "findings cache: on, off, or clean; -r always bypasses the cache since it mutates source")
+	cacheMaxMB = flag.Int64("cache-max-mb", 256, "evict least-recently-used cache entries once the on-disk cache exceeds this size, in MiB")
+)
+
+var (
+	fset      = token.NewFileSet()
+	exitCode  int
+	fileCount int
+	filterRx  *regexp.Regexp
+)
+
+func report(err error) {
+	scanner.PrintError(os.Stderr, err)
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: tryhard [flags] [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *deferRewrite {
+		*rewrite = true
+	}
+
+	if *filter != "" {
+		rx, err := regexp.Compile(*filter)
+		if err != nil {
+			report(err)
+			os.Exit(exitCode)
+		}
+		filterRx = rx
+	}
+
+	switch *outFormat {
+	case "text", "json", "sarif":
+		// ok
+	default:
+		report(fmt.Errorf("invalid -format %q: must be text, json, or sarif", *outFormat))
+		os.Exit(exitCode)
+	}
+
+	switch *cacheMode {
+	case "on", "off":
+		// ok
+	case "clean":
+		if err := cleanCache(); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	default:
+		report(fmt.Errorf("invalid -cache %q: must be on, off, or clean", *cacheMode))
+		os.Exit(exitCode)
+	}
+
+	paths := make(chan string)
+	errs := make(chan error)
+	go func() {
+		defer close(paths)
+		defer close(errs)
+		for i := 0; i < flag.NArg(); i++ {
+			path := flag.Arg(i)
+			switch dir, err := os.Stat(path); {
+			case err != nil:
+				errs <- err
+			case dir.IsDir():
+				filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
+					return visitFile(path, f, err, paths, errs)
+				})
+			default:
+				paths <- path
+			}
+		}
+	}()
+
+	rep := scanFiles(paths, errs)
+
+	if fileCount > 0 {
+		sortFindings(rep)
+		switch *outFormat {
+		case "json":
+			reportJSON(rep)
+		case "sarif":
+			reportSARIF(rep)
+		default:
+			if *list {
+				reportPositions(rep)
+			}
+			reportCounts(rep)
+		}
+	}
+
+	os.Exit(exitCode)
+}
+
+// fileResult is the outcome of processing a single file, produced by a
+// worker goroutine and merged into the overall *tryhard.Report by the main
+// goroutine.
+type fileResult struct {
+	report *tryhard.Report
+	err    error
+}
+
+// scanFiles reads Go file paths from paths and processes them using a pool
+// of runtime.GOMAXPROCS(0) worker goroutines, bounding the number of files
+// concurrently open via sem. It also drains errs, which carries errors from
+// the path-dispatching goroutine (failed os.Stat or filepath.Walk calls).
+// Both results and errs are merged into the overall report and exitCode on
+// this (single) goroutine, so no locking is needed for fileCount/exitCode.
+func scanFiles(paths <-chan string, errs <-chan error) *tryhard.Report {
+	numWorkers := runtime.GOMAXPROCS(0)
+	sem := make(chan struct{}, maxOpenFiles)
+	results := make(chan fileResult)
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				sem <- struct{}{}
+				rep, err := processFile(path)
+				<-sem
+				results <- fileResult{rep, err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	rep := new(tryhard.Report)
+	for errs != nil || results != nil {
+		select {
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			report(err)
+		case r, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			fileCount++
+			// Don't complain if a file was deleted in the meantime (i.e.
+			// the directory changed concurrently while running tryhard).
+			if r.err != nil {
+				if !os.IsNotExist(r.err) {
+					report(r.err)
+				}
+				continue
+			}
+			rep.Merge(r.report)
+		}
+	}
+	return rep
+}
+
+func processFile(filename string) (*tryhard.Report, error) {
+	var perm os.FileMode = 0644
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	perm = fi.Mode().Perm()
+
+	src, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// -r mutates source, so it always needs a fresh AST to rewrite; only
+	// read-only runs (counts and -l) may be served from the cache.
+	cacheable := *cacheMode == "on" && !*rewrite
+	var key string
+	if cacheable {
+		key = cacheKey(src)
+		if rep, ok := loadCache(key, filename, src); ok {
+			return rep, nil
+		}
+	}
+
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := tryhard.Options{VarName: *varname, Rewrite: *rewrite, DeferRewrite: *deferRewrite}
+	rep := tryhard.Analyze(fset, []*ast.File{file}, opts)
+	if cacheable {
+		storeCache(key, rep)
+	}
+	if len(rep.ModifiedFiles) == 0 || !*rewrite {
+		return rep, nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return rep, err
+	}
+	res := buf.Bytes()
+
+	// make a temporary backup before overwriting original
+	bakname, err := backupFile(filename+".", src, perm)
+	if err != nil {
+		return rep, err
+	}
+	err = ioutil.WriteFile(filename, res, perm)
+	if err != nil {
+		os.Rename(bakname, filename)
+		return rep, err
+	}
+	return rep, os.Remove(bakname)
+}
+
+func visitFile(path string, f os.FileInfo, err error, paths chan<- string, errs chan<- error) error {
+	if err == nil && !excluded(path) && isGoFile(f) {
+		paths <- path
+	} else if err != nil && !os.IsNotExist(err) {
+		errs <- err
+	}
+	return nil
+}
+
+func excluded(path string) bool {
+	return filterRx != nil && filterRx.MatchString(path)
+}
+
+func isGoFile(f os.FileInfo) bool {
+	// ignore non-Go files
+	name := f.Name()
+	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
+}
+
+const chmodSupported = runtime.GOOS != "windows"
+
+// backupFile writes data to a new file named filename<number> with permissions perm,
+// with <number randomly chosen such that the file name is unique. backupFile returns
+// the chosen file name.
+func backupFile(filename string, data []byte, perm os.FileMode) (string, error) {
+	// create backup file
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename))
+	if err != nil {
+		return "", err
+	}
+	bakname := f.Name()
+	if chmodSupported {
+		err = f.Chmod(perm)
+		if err != nil {
+			f.Close()
+			os.Remove(bakname)
+			return bakname, err
+		}
+	}
+
+	// write data to backup file
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	return bakname, err
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/griesemer/tryhard"
+)
+
+// TestScanFilesConcurrentErrors exercises scanFiles with a mix of processable
+// files and paths that fail os.Stat, so that results and errs are drained
+// concurrently from workers and the path-dispatch goroutine at once. Run
+// with -race, it catches data races on fileCount/exitCode such as the one
+// fixed by routing dispatch errors through errs instead of calling report
+// directly from the dispatch goroutine.
+func TestScanFilesConcurrentErrors(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for i := 0; i < 10; i++ {
+		name := filepath.Join(dir, "f"+string(rune('0'+i))+".go")
+		if err := os.WriteFile(name, []byte("package p\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, name)
+	}
+
+	before := exitCode
+	exitCode = 0
+	defer func() { exitCode = before }()
+
+	paths := make(chan string)
+	errs := make(chan error)
+	go func() {
+		defer close(paths)
+		defer close(errs)
+		for _, name := range want {
+			paths <- name
+		}
+		for i := 0; i < 10; i++ {
+			errs <- errors.New("synthetic dispatch error")
+		}
+	}()
+
+	beforeCount := fileCount
+	fileCount = 0
+	defer func() { fileCount = beforeCount }()
+
+	rep := scanFiles(paths, errs)
+
+	if fileCount != len(want) {
+		t.Errorf("fileCount = %d, want %d", fileCount, len(want))
+	}
+	if rep.Counts[tryhard.Func] != 0 {
+		t.Errorf("Counts[Func] = %d, want 0 (files have no funcs)", rep.Counts[tryhard.Func])
+	}
+	if exitCode != 2 {
+		t.Errorf("exitCode = %d, want 2 (synthetic errors should have been reported)", exitCode)
+	}
+}
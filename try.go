@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package tryhard
 
 import (
 	"go/ast"
@@ -11,46 +11,71 @@ import (
 )
 
 type funcInfo struct {
-	modified   bool       // indicates whether the function body was modified
-	sharedLast []ast.Expr // if <err> != nil { return ... zero ..., last } always the same; valid if != nil
+	report        *Report    // aggregated counts and findings for the enclosing Analyze call
+	opts          Options    // the Options this function is being analyzed (and possibly rewritten) with
+	funcName      string     // name of the enclosing function, for Finding.FuncName
+	modified      bool       // indicates whether the function body was modified
+	sharedLast    []ast.Expr // if <err> != nil { return ... zero ..., last } always the same; valid if != nil
+	sharedErrname string     // <err> name shared by all entries in sharedLast; valid if sharedLast != nil
 }
 
-// tryFile identifies statements in f that are potential candidates for `try`,
-// lists their positions (-l flag), or rewrites them in place using `try` (-r flag)
-// and sets *modified to true.
-func tryFile(f *ast.File, modified *bool) {
+// record counts k like fi.report.count, attributing the position (when n is
+// non-nil) to fi.funcName and errname.
+func (fi *funcInfo) record(k Kind, n ast.Node, errname string) {
+	fi.report.count(k, n, fi.funcName, errname)
+}
+
+// analyzeFile identifies statements in f that are potential candidates for
+// `try`, and, with opts.Rewrite, rewrites them in place. Counts and findings
+// are accumulated into r; f is appended to r.ModifiedFiles if it was changed.
+func analyzeFile(f *ast.File, opts Options, r *Report) {
+	modified := false
 	for _, d := range f.Decls {
-		if f, ok := d.(*ast.FuncDecl); ok {
-			count(Func, nil)
-			if hasErrorResult(f.Type) && f.Body != nil {
-				count(FuncError, nil)
-
-				fi := funcInfo{false, []ast.Expr{} /* mark as valid but empty */}
-				fi.tryBlock(f.Body)
-				if fi.modified {
-					*modified = true
-				}
+		fd, ok := d.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		r.count(Func, nil, "", "")
+		if !hasErrorResult(fd.Type) || fd.Body == nil {
+			continue
+		}
+		r.count(FuncError, nil, "", "")
 
-				if len(fi.sharedLast) > 1 {
-					// Return statements in `if <err> != nil` statements for
-					// `try` candidates share the same last expression. This
-					// is an indicator that deferred handling of that expression
-					// may be possible if there are no other error returns.
-					for _, last := range fi.sharedLast {
-						count(SharedLast, last)
-					}
-				}
+		fi := funcInfo{report: r, opts: opts, funcName: fd.Name.Name, sharedLast: []ast.Expr{} /* mark as valid but empty */}
+		fi.tryBlock(fd.Body)
+		if fi.modified {
+			modified = true
+		}
+
+		if len(fi.sharedLast) > 1 {
+			// Return statements in `if <err> != nil` statements that aren't
+			// bare try candidates all wrap the same expression (e.g.
+			// fmt.Errorf("...: %v", err)). If nothing else in the function
+			// returns a non-nil error, that expression can instead be
+			// applied once, via a deferred decorator.
+			wrapper := fi.sharedLast[0]
+			for _, last := range fi.sharedLast {
+				fi.record(DeferWrapCand, last, fi.sharedErrname)
+			}
+			if opts.Rewrite && opts.DeferRewrite && !hasForeignErrReturn(fd.Body, fi.sharedErrname, wrapper) {
+				fi.rewriteDeferWrap(fd, wrapper)
+			}
+			if fi.modified {
+				modified = true
 			}
 		}
 	}
+	if modified {
+		r.ModifiedFiles = append(r.ModifiedFiles, f)
+	}
 }
 
-// tryBlock is like tryFile but operates on a block b.
+// tryBlock is like analyzeFile but operates on a block b.
 func (fi *funcInfo) tryBlock(b *ast.BlockStmt) {
 	dirty := false // if set, b.List contains nil entries
 	var p ast.Stmt // previous statement
 	for i, s := range b.List {
-		count(Stmt, nil)
+		fi.record(Stmt, nil, "")
 		switch s := s.(type) {
 		case *ast.BlockStmt:
 			fi.tryBlock(s)
@@ -65,7 +90,7 @@ func (fi *funcInfo) tryBlock(b *ast.BlockStmt) {
 		case *ast.TypeSwitchStmt:
 			fi.tryBlock(s.Body)
 		case *ast.IfStmt:
-			count(If, nil)
+			fi.record(If, nil, "")
 			fi.tryBlock(s.Body)
 			if s, ok := s.Else.(*ast.BlockStmt); ok {
 				fi.tryBlock(s)
@@ -73,22 +98,22 @@ func (fi *funcInfo) tryBlock(b *ast.BlockStmt) {
 
 			// condition must be of the form: <err> != nil
 			// where <err> stands for the error variable name
-			errname := *varname
+			errname := fi.opts.VarName
 			if !isErrTest(s.Cond, &errname) {
 				break
 			}
-			count(IfErr, nil)
+			fi.record(IfErr, nil, "")
 
 			if s.Init == nil && isErrAssign(p, errname) && fi.isTryHandler(s, errname) {
 				// ..., <err> := <expr>
 				// if <err> != nil {
 				//         return ... zeroes ..., <err>
 				// }
-				count(TryCand, s)
+				fi.record(TryCand, s, errname)
 				if errname != "err" {
-					count(NonErrName, s.Cond)
+					fi.record(NonErrName, s.Cond, errname)
 				}
-				if *rewrite {
+				if fi.opts.Rewrite {
 					b.List[i-1] = rewriteAssign(p, s.End())
 					b.List[i] = nil // remove `if`
 					dirty = true
@@ -98,11 +123,11 @@ func (fi *funcInfo) tryBlock(b *ast.BlockStmt) {
 				// if ..., <err> := <expr>; <err> != nil {
 				//         return ... zeroes ..., <err>
 				// }
-				count(TryCand, s)
+				fi.record(TryCand, s, errname)
 				if errname != "err" {
-					count(NonErrName, s.Cond)
+					fi.record(NonErrName, s.Cond, errname)
 				}
-				if *rewrite {
+				if fi.opts.Rewrite {
 					b.List[i] = rewriteAssign(s.Init, s.End())
 					fi.modified = true
 				}
@@ -135,17 +160,21 @@ func (fi *funcInfo) isTryHandler(s *ast.IfStmt, errname string) bool {
 		if len(s.Body.List) > 1 {
 			k = ComplexBlock
 		}
-		count(k, s.Body)
+		fi.record(k, s.Body, errname)
 	}
 
 	// last must be <err>, if present
 	if last != nil && !isName(last, errname) {
 		ok = false
-		count(ReturnExpr, s.Body)
+		fi.record(ReturnExpr, s.Body, errname)
 		if fi.sharedLast != nil {
-			if len(fi.sharedLast) == 0 || equal(last, fi.sharedLast[0]) {
+			switch {
+			case len(fi.sharedLast) == 0:
+				fi.sharedLast = append(fi.sharedLast, last)
+				fi.sharedErrname = errname
+			case errname == fi.sharedErrname && equal(last, fi.sharedLast[0]):
 				fi.sharedLast = append(fi.sharedLast, last)
-			} else {
+			default:
 				fi.sharedLast = nil // invalidate
 			}
 		}
@@ -156,12 +185,150 @@ func (fi *funcInfo) isTryHandler(s *ast.IfStmt, errname string) bool {
 	// else block must be absent
 	if s.Else != nil {
 		ok = false
-		count(HasElse, s.Else)
+		fi.record(HasElse, s.Else, errname)
 	}
 
 	return ok
 }
 
+// rewriteDeferWrap rewrites f, whose error-returning if statements all wrap
+// <err> with the same wrapper expression (recorded as fi.sharedLast), to
+// apply that wrapper exactly once via a deferred decorator: the guarded
+// assignments become `try` calls, and
+//
+//	defer func() { if <err> != nil { <err> = wrapper } }()
+//
+// is prepended to the body. f's error result is named <err> if it is not
+// already named, so the deferred closure can assign to it.
+func (fi *funcInfo) rewriteDeferWrap(f *ast.FuncDecl, wrapper ast.Expr) {
+	errname := fi.sharedErrname
+	if !nameResult(f.Type, errname) {
+		return // result already has a different name; too risky to rewrite
+	}
+	fi.tryBlockDeferWrap(f.Body, errname, wrapper)
+	f.Body.List = append([]ast.Stmt{deferWrapStmt(errname, wrapper)}, f.Body.List...)
+	fi.modified = true
+}
+
+// tryBlockDeferWrap finds the `if <err> != nil { return ..., wrapper }`
+// statements that tryBlock left alone (because their last expression wasn't
+// bare <err>) and rewrites them to use try, now that wrapper is applied once
+// by the deferred decorator installed by rewriteDeferWrap.
+func (fi *funcInfo) tryBlockDeferWrap(b *ast.BlockStmt, errname string, wrapper ast.Expr) {
+	dirty := false // if set, b.List contains nil entries
+	var p ast.Stmt // previous statement
+	for i, s := range b.List {
+		switch s := s.(type) {
+		case *ast.BlockStmt:
+			fi.tryBlockDeferWrap(s, errname, wrapper)
+		case *ast.ForStmt:
+			fi.tryBlockDeferWrap(s.Body, errname, wrapper)
+		case *ast.RangeStmt:
+			fi.tryBlockDeferWrap(s.Body, errname, wrapper)
+		case *ast.SelectStmt:
+			fi.tryBlockDeferWrap(s.Body, errname, wrapper)
+		case *ast.SwitchStmt:
+			fi.tryBlockDeferWrap(s.Body, errname, wrapper)
+		case *ast.TypeSwitchStmt:
+			fi.tryBlockDeferWrap(s.Body, errname, wrapper)
+		case *ast.IfStmt:
+			fi.tryBlockDeferWrap(s.Body, errname, wrapper)
+			if isWrapCand(s, errname, wrapper) {
+				if s.Init == nil && isErrAssign(p, errname) {
+					b.List[i-1] = rewriteAssign(p, s.End())
+					b.List[i] = nil // remove `if`
+					dirty = true
+				} else if isErrAssign(s.Init, errname) {
+					b.List[i] = rewriteAssign(s.Init, s.End())
+				}
+			}
+		}
+		p = s
+	}
+
+	if dirty {
+		i := 0
+		for _, s := range b.List {
+			if s != nil {
+				b.List[i] = s
+				i++
+			}
+		}
+		b.List = b.List[:i]
+	}
+}
+
+// isWrapCand reports whether s is an `if <err> != nil { return ..., wrapper }`
+// statement with no else, whose last expression is exactly wrapper.
+func isWrapCand(s *ast.IfStmt, errname string, wrapper ast.Expr) bool {
+	if s.Else != nil {
+		return false
+	}
+	en := errname
+	if !isErrTest(s.Cond, &en) {
+		return false
+	}
+	isRet, last := isReturn(s.Body)
+	return isRet && last != nil && equal(last, wrapper)
+}
+
+// nameResult ensures sig's final (error) result is named errname, naming it
+// if it is currently unnamed. It reports false if the result already has a
+// different name, since renaming it could break existing references to it.
+func nameResult(sig *ast.FuncType, errname string) bool {
+	last := sig.Results.List[len(sig.Results.List)-1]
+	switch len(last.Names) {
+	case 0:
+		last.Names = []*ast.Ident{ast.NewIdent(errname)}
+		return true
+	case 1:
+		return last.Names[0].Name == errname
+	default:
+		return false
+	}
+}
+
+// deferWrapStmt returns `defer func() { if errname != nil { errname = wrapper } }()`.
+func deferWrapStmt(errname string, wrapper ast.Expr) ast.Stmt {
+	cond := &ast.BinaryExpr{X: ast.NewIdent(errname), Op: token.NEQ, Y: ast.NewIdent("nil")}
+	assign := &ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent(errname)}, Tok: token.ASSIGN, Rhs: []ast.Expr{wrapper}}
+	body := &ast.BlockStmt{List: []ast.Stmt{&ast.IfStmt{Cond: cond, Body: &ast.BlockStmt{List: []ast.Stmt{assign}}}}}
+	lit := &ast.FuncLit{Type: &ast.FuncType{Params: &ast.FieldList{}}, Body: body}
+	return &ast.DeferStmt{Call: &ast.CallExpr{Fun: lit}}
+}
+
+// hasForeignErrReturn reports whether body contains a return statement whose
+// last result is a non-nil expression other than a bare errname or wrapper
+// itself. Such a return would be wrongly re-wrapped by the deferred
+// decorator, so its presence disqualifies the function from rewriteDeferWrap.
+func hasForeignErrReturn(body *ast.BlockStmt, errname string, wrapper ast.Expr) bool {
+	foreign := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if foreign {
+			return false
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			// Returns inside a nested function literal belong to that
+			// literal, not to the function being rewritten; don't descend.
+			return false
+		}
+		ret, ok := n.(*ast.ReturnStmt)
+		if !ok {
+			return true
+		}
+		if len(ret.Results) == 0 {
+			return true
+		}
+		last := ret.Results[len(ret.Results)-1]
+		if isZero(last) || isName(last, errname) || equal(last, wrapper) {
+			return true
+		}
+		foreign = true
+		return false
+	})
+	return foreign
+}
+
 // rewriteAssign assumes that s is an assignment that is a potential candidate
 // for `try` and rewrites it accordingly. It returns the new assignment (or the
 // assignment's rhs if there's no lhs anymore).
@@ -192,8 +359,8 @@ func isBlanks(list []ast.Expr) bool {
 
 // asErrAssign reports whether s is an assignment statement of the form:
 //
-//      v1, v2, ... vn, <err>  = f()
-//      v1, v2, ... vn, <err> := f()
+//	v1, v2, ... vn, <err>  = f()
+//	v1, v2, ... vn, <err> := f()
 //
 // where the vi are arbitrary expressions or variables (n may also be 0),
 // <err> is the variable errname, and f() stands for a function call.
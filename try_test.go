@@ -0,0 +1,162 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tryhard
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// deferWrapTests exercises rewriteDeferWrap/tryBlockDeferWrap/hasForeignErrReturn
+// over the wrap-and-return patterns from examples.go's f/g functions.
+var deferWrapTests = []struct {
+	name string
+	src  string
+	want string // expected body of want, or "" if src is unchanged
+}{
+	{
+		name: "repeated wrapper is hoisted into a deferred decorator",
+		src: `package p
+
+func g(arg int) error {
+	err := h(arg)
+	if err != nil {
+		return fmt.Errorf("g failed for %v: %v", arg, err)
+	}
+
+	err = f(arg)
+	if err != nil {
+		return fmt.Errorf("g failed for %v: %v", arg, err)
+	}
+	return nil
+}
+`,
+		want: `package p
+
+func g(arg int) (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("g failed for %v: %v", arg, err)
+		}
+	}()
+	try(h(arg))
+
+	try(f(arg))
+	return nil
+}
+`,
+	},
+	{
+		name: "a shared report closure is hoisted into a deferred decorator",
+		src: `package p
+
+func f(arg int) error {
+	report := func(err error) error { return fmt.Errorf("f failed for %v: %v", arg, err) }
+
+	err := g(arg)
+	if err != nil {
+		return report(err)
+	}
+
+	err = h(arg)
+	if err != nil {
+		return report(err)
+	}
+	return nil
+}
+`,
+		want: `package p
+
+func f(arg int) (err error) {
+	defer func() {
+		if err != nil {
+			err = report(err)
+		}
+	}()
+	report := func(err error) error { return fmt.Errorf("f failed for %v: %v", arg, err) }
+
+	try(g(arg))
+
+	try(h(arg))
+	return nil
+}
+`,
+	},
+	{
+		name: "a foreign error return disqualifies the rewrite",
+		src: `package p
+
+func g(arg int) error {
+	err := h(arg)
+	if err != nil {
+		return fmt.Errorf("g failed for %v: %v", arg, err)
+	}
+
+	err = f(arg)
+	if err != nil {
+		return fmt.Errorf("g failed for %v: %v", arg, err)
+	}
+	return errors.New("unrelated")
+}
+`,
+		want: "", // unchanged: errors.New("unrelated") is a foreign error return
+	},
+	{
+		name: "a single wrapped return is not a wrap-and-return candidate",
+		src: `package p
+
+func g(arg int) error {
+	err := h(arg)
+	if err != nil {
+		return fmt.Errorf("g failed for %v: %v", arg, err)
+	}
+	return nil
+}
+`,
+		want: "", // unchanged: only one matching if, so sharedLast never exceeds 1
+	},
+}
+
+func TestRewriteDeferWrap(t *testing.T) {
+	for _, tt := range deferWrapTests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", tt.src, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+
+			opts := Options{Rewrite: true, DeferRewrite: true}
+			rep := Analyze(fset, []*ast.File{file}, opts)
+
+			var buf bytes.Buffer
+			if err := format.Node(&buf, fset, file); err != nil {
+				t.Fatalf("format.Node: %v", err)
+			}
+			got := buf.String()
+
+			want := tt.want
+			modified := len(rep.ModifiedFiles) > 0
+			if want == "" {
+				if modified {
+					t.Errorf("file was modified, want unchanged:\n%s", got)
+				}
+				return
+			}
+			if !modified {
+				t.Errorf("file was not modified, want:\n%s", want)
+				return
+			}
+			if strings.TrimSpace(got) != strings.TrimSpace(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
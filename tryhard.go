@@ -0,0 +1,137 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tryhard identifies, and optionally rewrites, statements in Go
+// source that are candidates for the `try` builtin. It is the library
+// underlying the tryhard command and the tryhard/analyzer Analyzer.
+package tryhard
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Kind categorizes a counted or reported node.
+type Kind int
+
+const (
+	Func = Kind(iota)
+	FuncError
+	Stmt
+	If
+	IfErr
+	TryCand
+	DeferWrapCand
+	NonErrName
+
+	// non-try candidates
+	ReturnExpr
+	SingleStmt
+	ComplexBlock
+	HasElse
+
+	numKinds = iota
+)
+
+// NumKinds is the number of Kind values.
+const NumKinds = Kind(numKinds)
+
+// KindDesc gives the long, human-oriented description of each Kind, for text
+// output.
+var KindDesc = [numKinds]string{
+	Func:          "functions (function literals are ignored)",
+	FuncError:     "functions returning an error",
+	Stmt:          "statements in functions returning an error",
+	If:            "if statements",
+	IfErr:         "if <err> != nil statements",
+	TryCand:       "try candidates",
+	DeferWrapCand: "wrap-and-return candidates for a deferred error decorator",
+	NonErrName:    `<err> name is different from "err"`,
+
+	// non-try candidates
+	ReturnExpr:   "{ return ... zero values ..., expr }",
+	SingleStmt:   "single statement then branch",
+	ComplexBlock: "complex then branch; cannot use try",
+	HasElse:      "non-empty else branch; cannot use try",
+}
+
+// KindName gives the short, stable identifier of each Kind, for structured
+// (e.g. JSON or SARIF) output.
+var KindName = [numKinds]string{
+	Func:          "Func",
+	FuncError:     "FuncError",
+	Stmt:          "Stmt",
+	If:            "If",
+	IfErr:         "IfErr",
+	TryCand:       "TryCand",
+	DeferWrapCand: "DeferWrapCand",
+	NonErrName:    "NonErrName",
+
+	ReturnExpr:   "ReturnExpr",
+	SingleStmt:   "SingleStmt",
+	ComplexBlock: "ComplexBlock",
+	HasElse:      "HasElse",
+}
+
+// Finding is a single position categorized as Kind, together with the
+// context (the enclosing function and the name of the error variable
+// involved) needed to make it useful without access to the source.
+type Finding struct {
+	Kind     Kind
+	Pos      token.Pos
+	FuncName string
+	ErrName  string
+}
+
+// Options customize Analyze's behavior.
+type Options struct {
+	// VarName is the name of the error variable; the empty string permits
+	// any name.
+	VarName string
+	// Rewrite enables rewriting try candidates (and, with DeferRewrite,
+	// wrap-and-return candidates) in place.
+	Rewrite bool
+	// DeferRewrite additionally rewrites wrap-and-return candidates using a
+	// deferred error decorator. It has no effect unless Rewrite is also set.
+	DeferRewrite bool
+}
+
+// Report holds the result of analyzing one or more files with Analyze.
+type Report struct {
+	Counts [numKinds]int
+	// Findings holds one entry per position-bearing count.
+	Findings []Finding
+	// ModifiedFiles holds the files (a subset of those passed to Analyze)
+	// that Options.Rewrite modified in place.
+	ModifiedFiles []*ast.File
+}
+
+// count adds 1 to the number of nodes categorized as k. If n != nil, count
+// also appends a Finding for it, attributing it to funcName and errName.
+func (r *Report) count(k Kind, n ast.Node, funcName, errName string) {
+	r.Counts[k]++
+	if n != nil {
+		r.Findings = append(r.Findings, Finding{k, n.Pos(), funcName, errName})
+	}
+}
+
+// Merge adds other's counts, findings, and modified files to r.
+func (r *Report) Merge(other *Report) {
+	for k, n := range other.Counts {
+		r.Counts[k] += n
+	}
+	r.Findings = append(r.Findings, other.Findings...)
+	r.ModifiedFiles = append(r.ModifiedFiles, other.ModifiedFiles...)
+}
+
+// Analyze identifies `try` candidates in files, and, if opts.Rewrite is set,
+// rewrites them in place. fset must be the *token.FileSet that files were
+// parsed with. The returned *Report aggregates the result across all files.
+func Analyze(fset *token.FileSet, files []*ast.File, opts Options) *Report {
+	r := new(Report)
+	for _, f := range files {
+		analyzeFile(f, opts, r)
+	}
+	return r
+}